@@ -0,0 +1,30 @@
+package badgerutils
+
+import "errors"
+
+// ErrKeyNotFound is returned by Txn.Get when the requested key doesn't exist.
+var ErrKeyNotFound = errors.New("badgerutils: key not found")
+
+// Txn is a minimal read/write transaction, shared by every storage engine
+// WriteStream can target.
+type Txn interface {
+	// Get looks up key, returning ErrKeyNotFound if it isn't present.
+	Get(key []byte) ([]byte, error)
+	// Set stages a key/value pair for the transaction.
+	Set(key, value []byte) error
+	// Commit applies the transaction's staged writes.
+	Commit() error
+	// Discard releases the transaction without applying it. It is safe to
+	// call after Commit.
+	Discard()
+}
+
+// Backend abstracts the storage engine WriteStream writes into, so ingest
+// code doesn't need to change when the underlying store does.
+type Backend interface {
+	// NewTransaction starts a new transaction. update must be true for any
+	// transaction that calls Set.
+	NewTransaction(update bool) Txn
+	// Close releases the backend's resources.
+	Close() error
+}
@@ -0,0 +1,59 @@
+package badgerutils
+
+import "github.com/dgraph-io/badger"
+
+// badgerBackend adapts a *badger.DB to the Backend interface.
+type badgerBackend struct {
+	db *badger.DB
+}
+
+// NewBadgerBackend opens (creating if necessary) the Badger database at dir
+// and wraps it as a Backend.
+func NewBadgerBackend(dir string) (Backend, error) {
+	db, err := openDB(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &badgerBackend{db: db}, nil
+}
+
+func (b *badgerBackend) NewTransaction(update bool) Txn {
+	return &badgerTxn{txn: b.db.NewTransaction(update)}
+}
+
+func (b *badgerBackend) Close() error {
+	return b.db.Close()
+}
+
+// badgerDB implements badgerAccessor for the Badger-only fast paths
+// ModeWriteBatch and ModeStreamWriter need.
+func (b *badgerBackend) badgerDB() *badger.DB {
+	return b.db
+}
+
+type badgerTxn struct {
+	txn *badger.Txn
+}
+
+func (t *badgerTxn) Get(key []byte) ([]byte, error) {
+	item, err := t.txn.Get(key)
+	if err == badger.ErrKeyNotFound {
+		return nil, ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return item.ValueCopy(nil)
+}
+
+func (t *badgerTxn) Set(key, value []byte) error {
+	return t.txn.Set(key, value)
+}
+
+func (t *badgerTxn) Commit() error {
+	return t.txn.Commit()
+}
+
+func (t *badgerTxn) Discard() {
+	t.txn.Discard()
+}
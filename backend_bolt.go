@@ -0,0 +1,79 @@
+package badgerutils
+
+import bolt "go.etcd.io/bbolt"
+
+// boltBucket is the single bucket boltBackend keeps all key/value pairs in.
+var boltBucket = []byte("badgerutils")
+
+// boltBackend adapts a *bolt.DB to the Backend interface.
+type boltBackend struct {
+	db *bolt.DB
+}
+
+// NewBoltBackend opens (creating if necessary) the bbolt database at path
+// and wraps it as a Backend.
+func NewBoltBackend(path string) (Backend, error) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, bucketErr := tx.CreateBucketIfNotExists(boltBucket)
+		return bucketErr
+	}); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &boltBackend{db: db}, nil
+}
+
+func (b *boltBackend) NewTransaction(update bool) Txn {
+	tx, err := b.db.Begin(update)
+	return &boltTxn{tx: tx, err: err}
+}
+
+func (b *boltBackend) Close() error {
+	return b.db.Close()
+}
+
+type boltTxn struct {
+	tx  *bolt.Tx
+	err error
+}
+
+func (t *boltTxn) Get(key []byte) ([]byte, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	value := t.tx.Bucket(boltBucket).Get(key)
+	if value == nil {
+		return nil, ErrKeyNotFound
+	}
+	// bolt only guarantees value's backing array is valid for the life of
+	// the transaction, so copy it out.
+	out := make([]byte, len(value))
+	copy(out, value)
+	return out, nil
+}
+
+func (t *boltTxn) Set(key, value []byte) error {
+	if t.err != nil {
+		return t.err
+	}
+	return t.tx.Bucket(boltBucket).Put(key, value)
+}
+
+func (t *boltTxn) Commit() error {
+	if t.err != nil {
+		return t.err
+	}
+	return t.tx.Commit()
+}
+
+func (t *boltTxn) Discard() {
+	if t.tx != nil {
+		t.tx.Rollback()
+	}
+}
@@ -0,0 +1,91 @@
+package badgerutils
+
+import (
+	"context"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/clientv3"
+)
+
+// etcdRequestTimeout bounds every etcd RPC etcdTxn issues, so a caller (or a
+// test probing for a local cluster) fails fast against an unreachable
+// endpoint instead of hanging.
+const etcdRequestTimeout = 2 * time.Second
+
+// etcdBackend adapts an etcd clientv3.Client to the Backend interface. Etcd
+// has no local transaction handle, so each Txn buffers its writes and applies
+// them as a single etcd transaction on Commit.
+type etcdBackend struct {
+	client *clientv3.Client
+}
+
+// NewEtcdBackend dials the etcd cluster at endpoints and wraps it as a
+// Backend.
+func NewEtcdBackend(endpoints []string) (Backend, error) {
+	client, err := clientv3.New(clientv3.Config{Endpoints: endpoints})
+	if err != nil {
+		return nil, err
+	}
+	return &etcdBackend{client: client}, nil
+}
+
+func (b *etcdBackend) NewTransaction(update bool) Txn {
+	return &etcdTxn{client: b.client}
+}
+
+func (b *etcdBackend) Close() error {
+	return b.client.Close()
+}
+
+type etcdTxn struct {
+	client  *clientv3.Client
+	ops     []clientv3.Op
+	pending map[string][]byte
+}
+
+// Get checks the txn's own buffered-but-uncommitted Sets before falling back
+// to the live cluster, so a WithDedup check sees writes made earlier in the
+// same txn instead of only what's already committed.
+func (t *etcdTxn) Get(key []byte) ([]byte, error) {
+	if value, ok := t.pending[string(key)]; ok {
+		return value, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), etcdRequestTimeout)
+	defer cancel()
+
+	resp, err := t.client.Get(ctx, string(key))
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, ErrKeyNotFound
+	}
+	return resp.Kvs[0].Value, nil
+}
+
+func (t *etcdTxn) Set(key, value []byte) error {
+	t.ops = append(t.ops, clientv3.OpPut(string(key), string(value)))
+	if t.pending == nil {
+		t.pending = make(map[string][]byte)
+	}
+	t.pending[string(key)] = value
+	return nil
+}
+
+func (t *etcdTxn) Commit() error {
+	if len(t.ops) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), etcdRequestTimeout)
+	defer cancel()
+
+	_, err := t.client.Txn(ctx).Then(t.ops...).Commit()
+	return err
+}
+
+func (t *etcdTxn) Discard() {
+	t.ops = nil
+	t.pending = nil
+}
@@ -0,0 +1,80 @@
+package badgerutils
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// etcdTestEndpoint is where getTestBackends looks for a local etcd cluster
+// to run the conformance suite against.
+const etcdTestEndpoint = "127.0.0.1:2379"
+
+// getTestBackends opens one instance of every supported Backend rooted under
+// a fresh temp directory, so conformance tests can run identically against
+// each storage engine. It always includes badger and bolt; it also dials
+// etcd at etcdTestEndpoint and includes it only if that dial and a real
+// round-trip both succeed within etcdRequestTimeout, logging and skipping it
+// otherwise.
+func getTestBackends(t *testing.T) map[string]Backend {
+	dir, err := os.Getwd()
+	require.Nil(t, err)
+	tmpDir, err := ioutil.TempDir(dir, "temp")
+	require.Nil(t, err)
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+
+	badgerBackend, err := NewBadgerBackend(path.Join(tmpDir, "badger"))
+	require.Nil(t, err)
+
+	boltBackend, err := NewBoltBackend(path.Join(tmpDir, "bolt.db"))
+	require.Nil(t, err)
+
+	backends := map[string]Backend{
+		"badger": badgerBackend,
+		"bolt":   boltBackend,
+	}
+
+	etcdBackend, err := NewEtcdBackend([]string{etcdTestEndpoint})
+	if err != nil {
+		t.Logf("skipping etcd backend: %v", err)
+		return backends
+	}
+
+	probe := etcdBackend.NewTransaction(false)
+	_, probeErr := probe.Get([]byte("__badgerutils_probe__"))
+	probe.Discard()
+	if probeErr != nil && probeErr != ErrKeyNotFound {
+		t.Logf("skipping etcd backend, %s unreachable: %v", etcdTestEndpoint, probeErr)
+		etcdBackend.Close()
+		return backends
+	}
+
+	backends["etcd"] = etcdBackend
+	return backends
+}
+
+func TestBackendConformance(t *testing.T) {
+	for name, backend := range getTestBackends(t) {
+		backend := backend
+		t.Run(name, func(t *testing.T) {
+			defer backend.Close()
+
+			txn := backend.NewTransaction(true)
+			require.Nil(t, txn.Set([]byte("key"), []byte("value")))
+			require.Nil(t, txn.Commit())
+
+			readTxn := backend.NewTransaction(false)
+			defer readTxn.Discard()
+
+			value, err := readTxn.Get([]byte("key"))
+			require.Nil(t, err)
+			require.Equal(t, []byte("value"), value)
+
+			_, err = readTxn.Get([]byte("missing-key"))
+			require.Equal(t, ErrKeyNotFound, err)
+		})
+	}
+}
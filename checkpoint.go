@@ -0,0 +1,120 @@
+package badgerutils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// checkpointKey is the reserved key WriteStream uses to persist ingest
+// progress inside the same backend it's writing to. Readers that walk every
+// key in the database (like ReadStream) must skip it; see isCheckpointKey.
+//
+// WithCheckpoint assumes no real record's encoded key ever equals this
+// value. GobCodec/JSONCodec/MsgpackCodec make that practically impossible,
+// but RawBytesCodec hands callers the raw key bytes directly: a record keyed
+// by exactly this string would be excluded by ReadStream forever, and (if
+// its value isn't exactly 8 bytes long) would make readCheckpoint
+// return an error instead of the real checkpoint. Avoid this key when using
+// WithCheckpoint with RawBytesCodec.
+var checkpointKey = []byte("__badgerutils_checkpoint__")
+
+// isCheckpointKey reports whether key is the reserved checkpoint key.
+func isCheckpointKey(key []byte) bool {
+	return bytes.Equal(key, checkpointKey)
+}
+
+// readCheckpoint returns the line number of the last batch a prior
+// checkpointed WriteStream run committed, or 0 if no checkpoint exists.
+func readCheckpoint(backend Backend) (int64, error) {
+	txn := backend.NewTransaction(false)
+	defer txn.Discard()
+
+	value, err := txn.Get(checkpointKey)
+	if errors.Is(err, ErrKeyNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(value) != 8 {
+		return 0, fmt.Errorf("badgerutils: checkpoint value is %d bytes, want 8 (a user record may be colliding with the reserved checkpoint key %q)", len(value), checkpointKey)
+	}
+
+	return int64(binary.BigEndian.Uint64(value)), nil
+}
+
+// setCheckpoint stages an update to the checkpoint inside txn, so it commits
+// atomically with whatever else the caller stages in txn.
+func setCheckpoint(txn Txn, line int64) error {
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, uint64(line))
+	return txn.Set(checkpointKey, value)
+}
+
+// checkpointTracker persists the checkpoint as a true low-watermark, safe
+// under the concurrency WithConcurrency allows even though batches commit
+// out of order. A max-only high-water mark isn't enough: if a later batch
+// (lines 21-30) commits and advances the checkpoint to 30 before an earlier
+// one (lines 1-10) commits, and the process then crashes, or the earlier
+// batch's own Commit subsequently fails and aborts WriteStream, lines 1-10
+// are gone but the checkpoint says otherwise — a resume would skip them
+// forever.
+//
+// register records each batch's lastLine in the increasing order
+// WriteStream dispatches batches in; complete reports that a batch actually
+// committed. The persisted checkpoint only ever advances past a lastLine
+// once every batch registered ahead of it has also completed, so it can
+// never outrun what's durably on disk.
+type checkpointTracker struct {
+	mu        sync.Mutex
+	pending   []int64
+	completed map[int64]bool
+	persisted int64
+}
+
+func newCheckpointTracker(resumedFrom int64) *checkpointTracker {
+	return &checkpointTracker{persisted: resumedFrom, completed: make(map[int64]bool)}
+}
+
+// register records that a batch ending at lastLine has been dispatched.
+// Callers must call register for every batch, in the same increasing
+// lastLine order WriteStream dispatches them in, before that batch can
+// complete.
+func (t *checkpointTracker) register(lastLine int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending = append(t.pending, lastLine)
+}
+
+// complete reports that the batch ending at lastLine committed successfully.
+// It persists the checkpoint as far as that now safely allows: past
+// lastLine, and past every earlier-registered batch still pending, only
+// once all of them have completed too.
+func (t *checkpointTracker) complete(backend Backend, lastLine int64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.completed[lastLine] = true
+
+	for len(t.pending) > 0 && t.completed[t.pending[0]] {
+		line := t.pending[0]
+
+		txn := backend.NewTransaction(true)
+		if err := setCheckpoint(txn, line); err != nil {
+			txn.Discard()
+			return err
+		}
+		if err := txn.Commit(); err != nil {
+			return err
+		}
+
+		delete(t.completed, line)
+		t.pending = t.pending[1:]
+		t.persisted = line
+	}
+
+	return nil
+}
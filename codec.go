@@ -0,0 +1,143 @@
+package badgerutils
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+
+	"github.com/vmihailenco/msgpack/v4"
+)
+
+// Codec encodes and decodes the interface{} values held in a KeyValue to and
+// from the bytes stored in the backend. WriteStream and ReadStream default to
+// GobCodec; pass a different Codec via WithCodec (WriteStream) or
+// WithReadCodec (ReadStream) to change the wire format, e.g. to make the
+// resulting DB readable by non-Go tools.
+type Codec interface {
+	EncodeKey(key interface{}) ([]byte, error)
+	DecodeKey(b []byte) (interface{}, error)
+	EncodeValue(value interface{}) ([]byte, error)
+	DecodeValue(b []byte) (interface{}, error)
+}
+
+// GobCodec encodes keys and values with encoding/gob and is WriteStream's
+// default. Each key and value is wrapped in a gobEnvelope before encoding, so
+// the stored bytes are not those of gob-encoding the bare value, and bytes
+// written that way are not decodable by GobCodec. Decoding back into a
+// concrete type requires that type to have been registered with gob.Register.
+type GobCodec struct{}
+
+// EncodeKey implements Codec.
+func (GobCodec) EncodeKey(key interface{}) ([]byte, error) { return gobEncode(key) }
+
+// DecodeKey implements Codec.
+func (GobCodec) DecodeKey(b []byte) (interface{}, error) { return gobDecode(b) }
+
+// EncodeValue implements Codec.
+func (GobCodec) EncodeValue(value interface{}) ([]byte, error) { return gobEncode(value) }
+
+// DecodeValue implements Codec.
+func (GobCodec) DecodeValue(b []byte) (interface{}, error) { return gobDecode(b) }
+
+// gobEnvelope carries a value through an interface-typed field so gob
+// records the concrete type alongside the bytes; gob.Encode(v) with v
+// statically typed interface{} would otherwise drop that type information,
+// making the result undecodable back into interface{}.
+type gobEnvelope struct {
+	V interface{}
+}
+
+func gobEncode(v interface{}) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := gob.NewEncoder(buf).Encode(gobEnvelope{V: v}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func gobDecode(b []byte) (interface{}, error) {
+	var env gobEnvelope
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&env); err != nil {
+		return nil, err
+	}
+	return env.V, nil
+}
+
+// JSONCodec encodes keys and values with encoding/json. It produces a Badger
+// DB whose records are readable by any language with a JSON decoder, at the
+// cost of decoding back into generic map[string]interface{}/float64 shapes
+// rather than the original Go types.
+type JSONCodec struct{}
+
+// EncodeKey implements Codec.
+func (JSONCodec) EncodeKey(key interface{}) ([]byte, error) { return json.Marshal(key) }
+
+// DecodeKey implements Codec.
+func (JSONCodec) DecodeKey(b []byte) (interface{}, error) { return jsonDecode(b) }
+
+// EncodeValue implements Codec.
+func (JSONCodec) EncodeValue(value interface{}) ([]byte, error) { return json.Marshal(value) }
+
+// DecodeValue implements Codec.
+func (JSONCodec) DecodeValue(b []byte) (interface{}, error) { return jsonDecode(b) }
+
+func jsonDecode(b []byte) (interface{}, error) {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// MsgpackCodec encodes keys and values with MessagePack, a compact binary
+// format with readers in most languages. Like JSONCodec, decoding recovers
+// generic shapes rather than the original Go types.
+type MsgpackCodec struct{}
+
+// EncodeKey implements Codec.
+func (MsgpackCodec) EncodeKey(key interface{}) ([]byte, error) { return msgpack.Marshal(key) }
+
+// DecodeKey implements Codec.
+func (MsgpackCodec) DecodeKey(b []byte) (interface{}, error) { return msgpackDecode(b) }
+
+// EncodeValue implements Codec.
+func (MsgpackCodec) EncodeValue(value interface{}) ([]byte, error) { return msgpack.Marshal(value) }
+
+// DecodeValue implements Codec.
+func (MsgpackCodec) DecodeValue(b []byte) (interface{}, error) { return msgpackDecode(b) }
+
+func msgpackDecode(b []byte) (interface{}, error) {
+	var v interface{}
+	if err := msgpack.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// RawBytesCodec passes keys and values through unchanged, requiring
+// lineToKeyValue/keyValueToLine to produce and consume []byte directly. It
+// avoids any encoding overhead for callers who already work in raw bytes.
+type RawBytesCodec struct{}
+
+// EncodeKey implements Codec.
+func (RawBytesCodec) EncodeKey(key interface{}) ([]byte, error) { return rawBytesEncode(key, "key") }
+
+// DecodeKey implements Codec.
+func (RawBytesCodec) DecodeKey(b []byte) (interface{}, error) { return b, nil }
+
+// EncodeValue implements Codec.
+func (RawBytesCodec) EncodeValue(value interface{}) ([]byte, error) {
+	return rawBytesEncode(value, "value")
+}
+
+// DecodeValue implements Codec.
+func (RawBytesCodec) DecodeValue(b []byte) (interface{}, error) { return b, nil }
+
+func rawBytesEncode(v interface{}, field string) ([]byte, error) {
+	b, ok := v.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("RawBytesCodec: %s is %T, not []byte", field, v)
+	}
+	return b, nil
+}
@@ -0,0 +1,51 @@
+package badgerutils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGobCodecRoundTrip(t *testing.T) {
+	codec := GobCodec{}
+
+	encoded, err := codec.EncodeValue("hello")
+	require.Nil(t, err)
+
+	decoded, err := codec.DecodeValue(encoded)
+	require.Nil(t, err)
+	require.Equal(t, "hello", decoded)
+}
+
+func TestJSONCodecRoundTrip(t *testing.T) {
+	codec := JSONCodec{}
+
+	encoded, err := codec.EncodeValue(map[string]interface{}{"a": float64(1)})
+	require.Nil(t, err)
+
+	decoded, err := codec.DecodeValue(encoded)
+	require.Nil(t, err)
+	require.Equal(t, map[string]interface{}{"a": float64(1)}, decoded)
+}
+
+func TestMsgpackCodecRoundTrip(t *testing.T) {
+	codec := MsgpackCodec{}
+
+	encoded, err := codec.EncodeValue("hello")
+	require.Nil(t, err)
+
+	decoded, err := codec.DecodeValue(encoded)
+	require.Nil(t, err)
+	require.Equal(t, "hello", decoded)
+}
+
+func TestRawBytesCodecRejectsNonBytes(t *testing.T) {
+	codec := RawBytesCodec{}
+
+	_, err := codec.EncodeValue("not bytes")
+	require.NotNil(t, err)
+
+	encoded, err := codec.EncodeValue([]byte("raw"))
+	require.Nil(t, err)
+	require.Equal(t, []byte("raw"), encoded)
+}
@@ -0,0 +1,10 @@
+package badgerutils
+
+import "github.com/dgraph-io/badger"
+
+// openDB opens (creating if necessary) the Badger database at dir using
+// sane defaults for CLI-style bulk ingest/export workloads.
+func openDB(dir string) (*badger.DB, error) {
+	opts := badger.DefaultOptions(dir)
+	return badger.Open(opts)
+}
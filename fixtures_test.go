@@ -0,0 +1,86 @@
+package badgerutils
+
+import (
+	"encoding/gob"
+	"fmt"
+	"strings"
+
+	"github.com/dgraph-io/badger"
+)
+
+// sampleRecord is the fixture value type shared by writer_test.go and
+// reader_test.go: a three-field CSV record round-tripped through
+// WriteStream/ReadStream.
+type sampleRecord struct {
+	Field1 string
+	Field2 string
+	Field3 string
+}
+
+func init() {
+	gob.Register(sampleRecord{})
+}
+
+// csvToSampleRecord parses a "field1,field2,field3" line into a KeyValue
+// keyed by the record's first field.
+func csvToSampleRecord(line string) (*KeyValue, error) {
+	fields := strings.Split(line, ",")
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("csvToSampleRecord: expected 3 fields, got %d in %q", len(fields), line)
+	}
+
+	return &KeyValue{
+		Key:   fields[0],
+		Value: sampleRecord{Field1: fields[0], Field2: fields[1], Field3: fields[2]},
+	}, nil
+}
+
+// sampleRecordToCSV is the inverse of csvToSampleRecord, for ReadStream.
+func sampleRecordToCSV(kv KeyValue) (string, error) {
+	record, ok := kv.Value.(sampleRecord)
+	if !ok {
+		return "", fmt.Errorf("sampleRecordToCSV: value is %T, not sampleRecord", kv.Value)
+	}
+	return strings.Join([]string{record.Field1, record.Field2, record.Field3}, ","), nil
+}
+
+// readDB opens the Badger database at dir and decodes every value, via
+// GobCodec, back into a sampleRecord, in key order.
+func readDB(dir string) ([]sampleRecord, error) {
+	db, err := openDB(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	codec := GobCodec{}
+
+	var records []sampleRecord
+	err = db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			var record sampleRecord
+			if valErr := item.Value(func(val []byte) error {
+				decoded, decodeErr := codec.DecodeValue(val)
+				if decodeErr != nil {
+					return decodeErr
+				}
+				r, ok := decoded.(sampleRecord)
+				if !ok {
+					return fmt.Errorf("readDB: value is %T, not sampleRecord", decoded)
+				}
+				record = r
+				return nil
+			}); valErr != nil {
+				return valErr
+			}
+			records = append(records, record)
+		}
+		return nil
+	})
+
+	return records, err
+}
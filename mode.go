@@ -0,0 +1,347 @@
+package badgerutils
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/dgraph-io/badger"
+	"github.com/dgraph-io/badger/pb"
+)
+
+// Mode selects how WriteStream commits key/value pairs into a Badger
+// database.
+type Mode int
+
+const (
+	// ModeTxn commits each batch in its own Badger transaction. This is
+	// WriteStream's original, default behavior, and the only mode compatible
+	// with WithCheckpoint, WithDedup, and non-Badger backends.
+	ModeTxn Mode = iota
+	// ModeWriteBatch commits through Badger's WriteBatch, which auto-splits
+	// oversized batches and skips WriteStream's own conflict-free commit
+	// bookkeeping.
+	ModeWriteBatch
+	// ModeStreamWriter bulk-loads via Badger's StreamWriter, which bypasses
+	// the LSM write path entirely for a large throughput win on cold loads.
+	// It requires an empty database and sorted, non-overlapping input;
+	// WriteStream buffers and sorts incoming records (spilling to disk past
+	// WithMemoryBudget) to satisfy that contract.
+	ModeStreamWriter
+)
+
+func (m Mode) String() string {
+	switch m {
+	case ModeTxn:
+		return "txn"
+	case ModeWriteBatch:
+		return "write_batch"
+	case ModeStreamWriter:
+		return "stream_writer"
+	default:
+		return fmt.Sprintf("Mode(%d)", int(m))
+	}
+}
+
+// defaultMemoryBudget bounds how much of the input ModeStreamWriter sorts in
+// memory before spilling a run to disk.
+const defaultMemoryBudget = 64 << 20 // 64MB
+
+// WithMode selects the commit strategy. Defaults to ModeTxn. ModeWriteBatch
+// and ModeStreamWriter only work against the Badger backend and cannot be
+// combined with WithCheckpoint or WithDedup.
+func WithMode(mode Mode) WriteStreamOption {
+	return func(c *writeStreamConfig) {
+		c.mode = mode
+	}
+}
+
+// WithMemoryBudget caps how many bytes of incoming records ModeStreamWriter
+// buffers before sorting what it has and spilling the run to a temp file.
+// Defaults to 64MB. It has no effect outside ModeStreamWriter.
+func WithMemoryBudget(bytes int64) WriteStreamOption {
+	return func(c *writeStreamConfig) {
+		c.memoryBudget = bytes
+	}
+}
+
+// badgerAccessor is implemented by backends that can hand back the
+// underlying *badger.DB, for the Badger-only fast paths ModeWriteBatch and
+// ModeStreamWriter need.
+type badgerAccessor interface {
+	badgerDB() *badger.DB
+}
+
+func writeStreamFastPath(reader io.Reader, backend Backend, lineToKeyValue func(string) (*KeyValue, error), cfg *writeStreamConfig) error {
+	if cfg.checkpoint || cfg.dedup {
+		return fmt.Errorf("badgerutils: WithCheckpoint and WithDedup require ModeTxn")
+	}
+
+	accessor, ok := backend.(badgerAccessor)
+	if !ok {
+		return fmt.Errorf("badgerutils: Mode %v requires the Badger backend", cfg.mode)
+	}
+
+	switch cfg.mode {
+	case ModeWriteBatch:
+		return writeStreamWithWriteBatch(reader, accessor.badgerDB(), lineToKeyValue, cfg)
+	case ModeStreamWriter:
+		return writeStreamWithStreamWriter(reader, accessor.badgerDB(), lineToKeyValue, cfg)
+	default:
+		return fmt.Errorf("badgerutils: unknown Mode %v", cfg.mode)
+	}
+}
+
+func writeStreamWithWriteBatch(reader io.Reader, db *badger.DB, lineToKeyValue func(string) (*KeyValue, error), cfg *writeStreamConfig) error {
+	start := time.Now()
+
+	wb := db.NewWriteBatch()
+	defer wb.Cancel()
+
+	var written count32
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		kv, err := stringToKVBytes(scanner.Text(), lineToKeyValue, cfg.codec)
+		if err != nil {
+			return err
+		}
+		if err := wb.Set(kv.Key, kv.Value); err != nil {
+			return err
+		}
+		written.increment(1)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if err := wb.Flush(); err != nil {
+		return err
+	}
+
+	log.Printf("Inserted %v records in %v (mode=%v)", written.get(), time.Since(start), ModeWriteBatch)
+	return nil
+}
+
+// streamWriterBatchSize is how many sorted records ModeStreamWriter groups
+// into a single pb.KVList before handing it to StreamWriter.Write.
+const streamWriterBatchSize = 1000
+
+func writeStreamWithStreamWriter(reader io.Reader, db *badger.DB, lineToKeyValue func(string) (*KeyValue, error), cfg *writeStreamConfig) error {
+	start := time.Now()
+
+	memoryBudget := cfg.memoryBudget
+	if memoryBudget <= 0 {
+		memoryBudget = defaultMemoryBudget
+	}
+
+	tmpDir, tmpErr := ioutil.TempDir("", "badgerutils-streamwriter")
+	if tmpErr != nil {
+		return tmpErr
+	}
+	defer os.RemoveAll(tmpDir)
+
+	runs, runsErr := sortedRuns(reader, lineToKeyValue, cfg.codec, tmpDir, memoryBudget)
+	if runsErr != nil {
+		return runsErr
+	}
+	defer func() {
+		for _, run := range runs {
+			run.Close()
+		}
+	}()
+
+	sw := db.NewStreamWriter()
+	if err := sw.Prepare(); err != nil {
+		return err
+	}
+
+	var written count32
+	list := &pb.KVList{}
+	flush := func() error {
+		if len(list.Kv) == 0 {
+			return nil
+		}
+		if err := sw.Write(list); err != nil {
+			return err
+		}
+		written.increment(int32(len(list.Kv)))
+		list = &pb.KVList{}
+		return nil
+	}
+
+	if err := mergeRuns(runs, func(kv kvBytes) error {
+		list.Kv = append(list.Kv, &pb.KV{Key: kv.Key, Value: kv.Value, Version: 1})
+		if len(list.Kv) == streamWriterBatchSize {
+			return flush()
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	if err := sw.Flush(); err != nil {
+		return err
+	}
+
+	log.Printf("Inserted %v records in %v (mode=%v)", written.get(), time.Since(start), ModeStreamWriter)
+	return nil
+}
+
+// run is a spilled, key-sorted slice of records, readable back one record at
+// a time.
+type run struct {
+	file    *os.File
+	decoder *gob.Decoder
+}
+
+func (r *run) next() (*kvBytes, error) {
+	var kv kvBytes
+	if err := r.decoder.Decode(&kv); err != nil {
+		return nil, err
+	}
+	return &kv, nil
+}
+
+func (r *run) Close() error {
+	return r.file.Close()
+}
+
+// sortedRuns scans reader, sorting records into key order in memory and
+// spilling each chunk to its own file in tmpDir as soon as it reaches
+// memoryBudget bytes (so a chunk can exceed the budget by its last record).
+// The returned runs are each internally sorted; mergeRuns produces the fully
+// sorted stream ModeStreamWriter's contract requires.
+func sortedRuns(reader io.Reader, lineToKeyValue func(string) (*KeyValue, error), codec Codec, tmpDir string, memoryBudget int64) ([]*run, error) {
+	var runs []*run
+	var chunk []kvBytes
+	var chunkSize int64
+
+	spill := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		sort.Slice(chunk, func(i, j int) bool { return bytes.Compare(chunk[i].Key, chunk[j].Key) < 0 })
+
+		r, err := writeRun(tmpDir, chunk)
+		if err != nil {
+			return err
+		}
+		runs = append(runs, r)
+		chunk = nil
+		chunkSize = 0
+		return nil
+	}
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		kv, err := stringToKVBytes(scanner.Text(), lineToKeyValue, codec)
+		if err != nil {
+			return nil, err
+		}
+		chunk = append(chunk, *kv)
+		chunkSize += int64(len(kv.Key) + len(kv.Value))
+		if chunkSize >= memoryBudget {
+			if err := spill(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := spill(); err != nil {
+		return nil, err
+	}
+
+	return runs, nil
+}
+
+func writeRun(tmpDir string, chunk []kvBytes) (*run, error) {
+	file, err := ioutil.TempFile(tmpDir, "run")
+	if err != nil {
+		return nil, err
+	}
+
+	encoder := gob.NewEncoder(file)
+	for _, kv := range chunk {
+		if err := encoder.Encode(kv); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &run{file: file, decoder: gob.NewDecoder(bufio.NewReader(file))}, nil
+}
+
+// mergeItem is a run's next unconsumed record, ordered into a min-heap by
+// key so mergeRuns can emit records across all runs in sorted order.
+type mergeItem struct {
+	kv     kvBytes
+	runIdx int
+}
+
+type mergeHeap []mergeItem
+
+func (h mergeHeap) Len() int            { return len(h) }
+func (h mergeHeap) Less(i, j int) bool  { return bytes.Compare(h[i].kv.Key, h[j].kv.Key) < 0 }
+func (h mergeHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *mergeHeap) Push(x interface{}) { *h = append(*h, x.(mergeItem)) }
+func (h *mergeHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
+// mergeRuns performs a k-way merge of the already-sorted runs, calling emit
+// with each record in overall key order.
+func mergeRuns(runs []*run, emit func(kvBytes) error) error {
+	h := &mergeHeap{}
+	heap.Init(h)
+
+	for i, r := range runs {
+		kv, err := r.next()
+		if err == io.EOF {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		heap.Push(h, mergeItem{kv: *kv, runIdx: i})
+	}
+
+	for h.Len() > 0 {
+		item := heap.Pop(h).(mergeItem)
+		if err := emit(item.kv); err != nil {
+			return err
+		}
+
+		next, err := runs[item.runIdx].next()
+		if err == io.EOF {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		heap.Push(h, mergeItem{kv: *next, runIdx: item.runIdx})
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package badgerutils
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteStreamWithWriteBatchMode(t *testing.T) {
+	dir, err := os.Getwd()
+	require.Nil(t, err)
+	tmpDir, err := ioutil.TempDir(dir, "temp")
+	require.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	dbPath := path.Join(tmpDir, "path", "to", "db")
+
+	reader := strings.NewReader(`field11,field12,field13
+field21,field22,field23
+field31,field32,field33`)
+	err = WriteStream(reader, dbPath, 2, csvToSampleRecord, WithMode(ModeWriteBatch))
+	require.Nil(t, err)
+
+	writtenSampleRecords, err := readDB(dbPath)
+	require.Nil(t, err)
+	require.Equal(t, 3, len(writtenSampleRecords))
+	require.EqualValues(t, writtenSampleRecords[0], sampleRecord{"field11", "field12", "field13"})
+	require.EqualValues(t, writtenSampleRecords[1], sampleRecord{"field21", "field22", "field23"})
+	require.EqualValues(t, writtenSampleRecords[2], sampleRecord{"field31", "field32", "field33"})
+}
+
+func TestWriteStreamWithStreamWriterMode(t *testing.T) {
+	dir, err := os.Getwd()
+	require.Nil(t, err)
+	tmpDir, err := ioutil.TempDir(dir, "temp")
+	require.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	dbPath := path.Join(tmpDir, "path", "to", "db")
+
+	// ModeStreamWriter requires sorted, non-overlapping input; feed it out of
+	// key order to exercise WriteStream's own sort-and-merge.
+	reader := strings.NewReader(`field31,field32,field33
+field11,field12,field13
+field21,field22,field23`)
+	err = WriteStream(reader, dbPath, 2, csvToSampleRecord, WithMode(ModeStreamWriter))
+	require.Nil(t, err)
+
+	writtenSampleRecords, err := readDB(dbPath)
+	require.Nil(t, err)
+	require.Equal(t, 3, len(writtenSampleRecords))
+	require.EqualValues(t, writtenSampleRecords[0], sampleRecord{"field11", "field12", "field13"})
+	require.EqualValues(t, writtenSampleRecords[1], sampleRecord{"field21", "field22", "field23"})
+	require.EqualValues(t, writtenSampleRecords[2], sampleRecord{"field31", "field32", "field33"})
+}
+
+func TestMergeRunsProducesSortedOutput(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "badgerutils-mergeruns")
+	require.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	runA, err := writeRun(tmpDir, []kvBytes{{Key: []byte("a"), Value: []byte("1")}, {Key: []byte("c"), Value: []byte("3")}})
+	require.Nil(t, err)
+	runB, err := writeRun(tmpDir, []kvBytes{{Key: []byte("b"), Value: []byte("2")}, {Key: []byte("d"), Value: []byte("4")}})
+	require.Nil(t, err)
+	defer runA.Close()
+	defer runB.Close()
+
+	var keys []string
+	err = mergeRuns([]*run{runA, runB}, func(kv kvBytes) error {
+		keys = append(keys, string(kv.Key))
+		return nil
+	})
+	require.Nil(t, err)
+	require.Equal(t, []string{"a", "b", "c", "d"}, keys)
+}
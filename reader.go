@@ -0,0 +1,139 @@
+package badgerutils
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger"
+	"github.com/dgraph-io/badger/pb"
+)
+
+// ReadStreamOption configures the Badger Stream framework used by ReadStream.
+type ReadStreamOption func(*readStreamConfig)
+
+type readStreamConfig struct {
+	numGo     int
+	prefix    []byte
+	chooseKey func(item *badger.Item) bool
+	codec     Codec
+}
+
+// WithNumGo sets the number of goroutines Badger's Stream framework uses to
+// scan key ranges concurrently. Defaults to 16.
+func WithNumGo(numGo int) ReadStreamOption {
+	return func(c *readStreamConfig) {
+		c.numGo = numGo
+	}
+}
+
+// WithPrefix restricts ReadStream to keys sharing the given prefix.
+func WithPrefix(prefix []byte) ReadStreamOption {
+	return func(c *readStreamConfig) {
+		c.prefix = prefix
+	}
+}
+
+// WithChooseKey installs a predicate used to selectively skip keys while
+// streaming. Badger's Stream.ChooseKey consults it for every key except the
+// reserved checkpoint key, which ReadStream always skips.
+func WithChooseKey(chooseKey func(item *badger.Item) bool) ReadStreamOption {
+	return func(c *readStreamConfig) {
+		c.chooseKey = chooseKey
+	}
+}
+
+// WithReadCodec selects the Codec used to decode keys and values. Defaults to
+// GobCodec and must match the Codec the database was written with.
+func WithReadCodec(codec Codec) ReadStreamOption {
+	return func(c *readStreamConfig) {
+		c.codec = codec
+	}
+}
+
+// ReadStream walks every key/value pair in the Badger database at dir using
+// Badger's Stream framework, decodes each entry back into a KeyValue with the
+// configured Codec (WithReadCodec; defaults to GobCodec and must match the
+// Codec the database was written with), converts it to a line with
+// keyValueToLine, and writes the newline-terminated result to writer. It is
+// the read-side counterpart to WriteStream, letting a Badger DB be piped
+// through shell tools the same way WriteStream ingests one.
+func ReadStream(dir string, writer io.Writer, keyValueToLine func(KeyValue) (string, error), opts ...ReadStreamOption) error {
+	cfg := &readStreamConfig{numGo: 16, codec: GobCodec{}}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	db, dbErr := openDB(dir)
+	if dbErr != nil {
+		return dbErr
+	}
+	defer db.Close()
+
+	start := time.Now()
+
+	bufWriter := bufio.NewWriter(writer)
+	var writeMu sync.Mutex
+	var lineCount count32
+
+	stream := db.NewStream()
+	stream.NumGo = cfg.numGo
+	stream.Prefix = cfg.prefix
+
+	// The reserved checkpoint key (see checkpoint.go) isn't a real record and
+	// must never reach keyValueToLine; always exclude it, on top of whatever
+	// selection the caller asked for via WithChooseKey.
+	userChooseKey := cfg.chooseKey
+	stream.ChooseKey = func(item *badger.Item) bool {
+		if isCheckpointKey(item.Key()) {
+			return false
+		}
+		if userChooseKey != nil {
+			return userChooseKey(item)
+		}
+		return true
+	}
+
+	stream.KeyToList = func(key []byte, itr *badger.Iterator) (*pb.KVList, error) {
+		return stream.ToList(key, itr)
+	}
+
+	stream.Send = func(list *pb.KVList) error {
+		for _, kv := range list.Kv {
+			keyValue, decodeErr := kvBytesToKeyValue(&kvBytes{Key: kv.Key, Value: kv.Value}, cfg.codec)
+			if decodeErr != nil {
+				return decodeErr
+			}
+
+			line, lineErr := keyValueToLine(*keyValue)
+			if lineErr != nil {
+				return lineErr
+			}
+
+			writeMu.Lock()
+			_, writeErr := bufWriter.WriteString(line + "\n")
+			writeMu.Unlock()
+			if writeErr != nil {
+				return writeErr
+			}
+
+			lineCount.increment(1)
+		}
+		return nil
+	}
+
+	if streamErr := stream.Orchestrate(context.Background()); streamErr != nil {
+		return streamErr
+	}
+
+	if flushErr := bufWriter.Flush(); flushErr != nil {
+		return flushErr
+	}
+
+	elapsed := time.Since(start)
+	log.Printf("Exported %v records in %v", lineCount.get(), elapsed)
+	return nil
+}
@@ -0,0 +1,56 @@
+package badgerutils
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadStream(t *testing.T) {
+	dir, err := os.Getwd()
+	require.Nil(t, err)
+	tmpDir, err := ioutil.TempDir(dir, "temp")
+	require.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	dbPath := path.Join(tmpDir, "path", "to", "db")
+
+	reader := strings.NewReader(`field11,field12,field13
+field21,field22,field23
+field31,field32,field33`)
+	err = WriteStream(reader, dbPath, 2, csvToSampleRecord)
+	require.Nil(t, err)
+
+	var out bytes.Buffer
+	err = ReadStream(dbPath, &out, sampleRecordToCSV)
+	require.Nil(t, err)
+
+	lines := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+	require.Equal(t, 3, len(lines))
+}
+
+func TestReadStreamSkipsCheckpointKey(t *testing.T) {
+	dir, err := os.Getwd()
+	require.Nil(t, err)
+	tmpDir, err := ioutil.TempDir(dir, "temp")
+	require.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	dbPath := path.Join(tmpDir, "path", "to", "db")
+
+	reader := strings.NewReader(`field11,field12,field13`)
+	err = WriteStream(reader, dbPath, 2, csvToSampleRecord, WithCheckpoint())
+	require.Nil(t, err)
+
+	var out bytes.Buffer
+	err = ReadStream(dbPath, &out, sampleRecordToCSV)
+	require.Nil(t, err)
+
+	lines := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+	require.Equal(t, 1, len(lines))
+}
@@ -2,20 +2,75 @@ package badgerutils
 
 import (
 	"bufio"
-	"bytes"
-	"encoding/gob"
-	"fmt"
+	"context"
+	"errors"
 	"io"
 	"log"
 	"os"
-	"strings"
-	"sync"
+	"runtime"
 	"sync/atomic"
 	"time"
 
-	"github.com/dgraph-io/badger"
+	"golang.org/x/sync/errgroup"
 )
 
+// WriteStreamOption configures WriteStream and WriteStreamWithBackend.
+type WriteStreamOption func(*writeStreamConfig)
+
+type writeStreamConfig struct {
+	codec        Codec
+	checkpoint   bool
+	dedup        bool
+	concurrency  int
+	mode         Mode
+	memoryBudget int64
+}
+
+// WithCodec selects the Codec used to encode keys and values. Defaults to
+// GobCodec.
+func WithCodec(codec Codec) WriteStreamOption {
+	return func(c *writeStreamConfig) {
+		c.codec = codec
+	}
+}
+
+// WithCheckpoint enables checkpointing. On start, WriteStream resumes after
+// the last line number a prior checkpointed run committed, reading it from a
+// reserved key inside the backend; after every batch it durably advances
+// that checkpoint once the batch's writes have committed. This makes
+// long-running imports safe to resume after a crash or restart. Combined
+// with WithDedup, re-running a partially completed import (or re-feeding
+// duplicate keys within one run) is idempotent rather than merely retriable
+// - see WithDedup for why that requires serializing batches. See
+// checkpointKey for a caveat when combined with RawBytesCodec.
+func WithCheckpoint() WriteStreamOption {
+	return func(c *writeStreamConfig) {
+		c.checkpoint = true
+	}
+}
+
+// WithDedup enables skip-if-present writes: before setting a key, WriteStream
+// checks whether it's already in the backend and skips it if so. Combined
+// with WithCheckpoint, this makes re-running a partially completed import
+// idempotent. WithDedup forces batches to commit one at a time regardless of
+// WithConcurrency, since deduping relies on each batch's Get seeing every
+// earlier batch's Set.
+func WithDedup() WriteStreamOption {
+	return func(c *writeStreamConfig) {
+		c.dedup = true
+	}
+}
+
+// WithConcurrency caps how many batches WriteStream commits at once. Defaults
+// to runtime.NumCPU(). Without a cap, a large input spawns one goroutine per
+// full batch, which can overwhelm the backend and OOM the process. Has no
+// effect when combined with WithDedup; see WithDedup.
+func WithConcurrency(concurrency int) WriteStreamOption {
+	return func(c *writeStreamConfig) {
+		c.concurrency = concurrency
+	}
+}
+
 // KeyValue struct defines a Key and a Value empty interface to be translated into a record.
 type KeyValue struct {
 	Key   interface{}
@@ -37,93 +92,209 @@ func (c *count32) get() int32 {
 	return atomic.LoadInt32((*int32)(c))
 }
 
-func stringToKVBytes(str string, lineToKeyValue func(string) (*KeyValue, error)) (*kvBytes, error) {
+func stringToKVBytes(str string, lineToKeyValue func(string) (*KeyValue, error), codec Codec) (*kvBytes, error) {
 	record, parseErr := lineToKeyValue(str)
 	if parseErr != nil {
 		return nil, parseErr
 	}
 
-	keyBuf := &bytes.Buffer{}
-	if keyErr := gob.NewEncoder(keyBuf).Encode(record.Key); keyErr != nil {
+	keyBytes, keyErr := codec.EncodeKey(record.Key)
+	if keyErr != nil {
 		return nil, keyErr
 	}
 
-	valBuf := &bytes.Buffer{}
-	if valErr := gob.NewEncoder(valBuf).Encode(record.Value); valErr != nil {
+	valueBytes, valErr := codec.EncodeValue(record.Value)
+	if valErr != nil {
 		return nil, valErr
 	}
 
 	return &kvBytes{
-		Key:   keyBuf.Bytes(),
-		Value: valBuf.Bytes(),
+		Key:   keyBytes,
+		Value: valueBytes,
 	}, nil
 }
 
-func writeBatch(kvs []kvBytes, db *badger.DB, cherr chan error, done func(int32)) {
-	txn := db.NewTransaction(true)
+func kvBytesToKeyValue(kv *kvBytes, codec Codec) (*KeyValue, error) {
+	key, keyErr := codec.DecodeKey(kv.Key)
+	if keyErr != nil {
+		return nil, keyErr
+	}
+
+	value, valErr := codec.DecodeValue(kv.Value)
+	if valErr != nil {
+		return nil, valErr
+	}
+
+	return &KeyValue{Key: key, Value: value}, nil
+}
+
+// batch is a group of key/value pairs pending a single commit, tagged with
+// the input line number of its last record so a checkpoint can be persisted
+// alongside it.
+type batch struct {
+	kvs      []kvBytes
+	lastLine int64
+}
+
+func writeBatch(ctx context.Context, b batch, backend Backend, cfg *writeStreamConfig, checkpoint *checkpointTracker, done func(written, skipped int32)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	txn := backend.NewTransaction(true)
 	defer txn.Discard()
 
-	for _, kv := range kvs {
+	var written, skipped int32
+	for _, kv := range b.kvs {
+		if cfg.dedup {
+			if _, err := txn.Get(kv.Key); err == nil {
+				skipped++
+				continue
+			} else if !errors.Is(err, ErrKeyNotFound) {
+				return err
+			}
+		}
+
 		if err := txn.Set(kv.Key, kv.Value); err != nil {
-			cherr <- err
+			return err
 		}
+		written++
 	}
 
-	txn.Commit(func(err error) {
-		if err != nil {
-			cherr <- err
+	if err := txn.Commit(); err != nil {
+		return err
+	}
+
+	// Batches can commit out of order under concurrency, so the checkpoint is
+	// advanced through checkpointTracker rather than staged in this txn: it
+	// only persists past b.lastLine once every batch registered ahead of it
+	// has also completed, instead of trusting whichever batch's Commit lands
+	// last.
+	if checkpoint != nil {
+		if err := checkpoint.complete(backend, b.lastLine); err != nil {
+			return err
 		}
-		done(int32(len(kvs)))
-	})
+	}
+
+	done(written, skipped)
+	return nil
 }
 
-// WriteStream translates io.Reader stream into key/value pairs that are written into the Badger.
-// lineToKeyValue function parameter defines how stdin is translated to a value and how to define a key
-// from that value.
-func WriteStream(reader io.Reader, dir string, batchSize int, lineToKeyValue func(string) (*KeyValue, error)) error {
+// WriteStream translates io.Reader stream into key/value pairs that are written into a Badger
+// database at dir. lineToKeyValue function parameter defines how stdin is translated to a value
+// and how to define a key from that value. It is a thin wrapper around WriteStreamWithBackend for
+// the common case of writing directly to Badger.
+func WriteStream(reader io.Reader, dir string, batchSize int, lineToKeyValue func(string) (*KeyValue, error), opts ...WriteStreamOption) error {
 	if mkdirErr := os.MkdirAll(dir, os.ModePerm); mkdirErr != nil {
 		return mkdirErr
 	}
 
-	db, dbErr := openDB(dir)
-	if dbErr != nil {
-		return dbErr
+	backend, backendErr := NewBadgerBackend(dir)
+	if backendErr != nil {
+		return backendErr
+	}
+	defer backend.Close()
+
+	return WriteStreamWithBackend(reader, backend, batchSize, lineToKeyValue, opts...)
+}
+
+// WriteStreamWithBackend translates io.Reader stream into key/value pairs that are written into
+// backend. lineToKeyValue function parameter defines how stdin is translated to a value and how to
+// define a key from that value. Use this directly to target a storage engine other than Badger.
+func WriteStreamWithBackend(reader io.Reader, backend Backend, batchSize int, lineToKeyValue func(string) (*KeyValue, error), opts ...WriteStreamOption) error {
+	cfg := &writeStreamConfig{codec: GobCodec{}, concurrency: runtime.NumCPU(), memoryBudget: defaultMemoryBudget}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.concurrency <= 0 {
+		cfg.concurrency = runtime.NumCPU()
+	}
+	// WithDedup's Get-then-Set check conflicts with itself across
+	// concurrently-dispatched batches: Badger registers every Get as a
+	// read-conflict key even on ErrKeyNotFound, so two in-flight batches
+	// sharing a key reliably fail one of their Commits with
+	// badger.ErrConflict instead of deduping. Serialize batches so a key can
+	// only ever be in flight in one transaction at a time.
+	if cfg.dedup {
+		cfg.concurrency = 1
+	}
+
+	if cfg.mode != ModeTxn {
+		return writeStreamFastPath(reader, backend, lineToKeyValue, cfg)
 	}
-	defer db.Close()
 
 	start := time.Now()
 
-	// Wait group ensures all transactions are committed before reading errors from channel
-	var wg sync.WaitGroup
-	var kvCount count32
-	done := func(processedCount int32) {
-		kvCount.increment(processedCount)
-		log.Printf("Records: %v\n", int32(kvCount))
-		wg.Done()
+	var resumedFrom int64
+	var checkpoint *checkpointTracker
+	if cfg.checkpoint {
+		var checkpointErr error
+		resumedFrom, checkpointErr = readCheckpoint(backend)
+		if checkpointErr != nil {
+			return checkpointErr
+		}
+		checkpoint = newCheckpointTracker(resumedFrom)
+	}
+
+	group, ctx := errgroup.WithContext(context.Background())
+	sem := make(chan struct{}, cfg.concurrency)
+
+	var writtenCount, skippedCount count32
+	done := func(written, skipped int32) {
+		writtenCount.increment(written)
+		skippedCount.increment(skipped)
+		log.Printf("Records: %v\n", writtenCount.get())
+	}
+
+	// dispatch gates batch commits behind sem so at most cfg.concurrency run at
+	// once; group.Go cancels ctx and stops accepting new batches on the first
+	// commit error instead of collecting errors until the end.
+	dispatch := func(b batch) bool {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return false
+		}
+		// register before dispatching so the tracker's pending queue stays in
+		// the same increasing lastLine order batches are handed out in, even
+		// though the batches themselves then commit concurrently.
+		if checkpoint != nil {
+			checkpoint.register(b.lastLine)
+		}
+		group.Go(func() error {
+			defer func() { <-sem }()
+			return writeBatch(ctx, b, backend, cfg, checkpoint, done)
+		})
+		return true
 	}
 
 	kvBatch := make([]kvBytes, 0)
-	cherr := make(chan error)
 
 	// Read from stream and write key/values in batches
 	scanner := bufio.NewScanner(reader)
+	var lineNum int64
 	for scanner.Scan() {
-		kv, err := stringToKVBytes(scanner.Text(), lineToKeyValue)
+		lineNum++
+		if cfg.checkpoint && lineNum <= resumedFrom {
+			continue
+		}
+
+		kv, err := stringToKVBytes(scanner.Text(), lineToKeyValue, cfg.codec)
 		if err != nil {
 			return err
 		}
 		kvBatch = append(kvBatch, *kv)
 		if len(kvBatch) == batchSize {
-			wg.Add(1)
-			go writeBatch(kvBatch, db, cherr, done)
+			if !dispatch(batch{kvs: kvBatch, lastLine: lineNum}) {
+				break
+			}
 			kvBatch = make([]kvBytes, 0)
 		}
 	}
 
 	// Write remaining key/values
 	if len(kvBatch) > 0 {
-		wg.Add(1)
-		writeBatch(kvBatch, db, cherr, done)
+		dispatch(batch{kvs: kvBatch, lastLine: lineNum})
 	}
 
 	// Read and handle errors from stream
@@ -131,21 +302,12 @@ func WriteStream(reader io.Reader, dir string, batchSize int, lineToKeyValue fun
 		return streamErr
 	}
 
-	wg.Wait()
-	close(cherr)
-
-	// Read and handle transaction errors
-	errs := make([]string, 0)
-	for err := range cherr {
-		errs = append(errs, fmt.Sprintf("%v", err))
-	}
-
-	if len(errs) > 0 {
-		return fmt.Errorf("Errors inserting records:\n%v", strings.Join(errs, "\n"))
+	if err := group.Wait(); err != nil {
+		return err
 	}
 
 	end := time.Now()
 	elapsed := end.Sub(start)
-	log.Printf("Inserted %v records in %v", kvCount.get(), elapsed)
+	log.Printf("Inserted records in %v (resumed_from=%v, skipped=%v, written=%v)", elapsed, resumedFrom, skippedCount.get(), writtenCount.get())
 	return nil
 }